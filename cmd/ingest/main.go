@@ -0,0 +1,85 @@
+// cmd/ingest はローカルファイルをチャンク分割・埋め込み・索引付けしてOpenSearchに投入するCLI
+package main
+
+import (
+	"context"
+	"flag"
+	"fmt"
+	"log"
+	"os"
+	"path/filepath"
+
+	"github.com/aws/aws-sdk-go-v2/config"
+	"github.com/aws/aws-sdk-go-v2/service/bedrockruntime"
+
+	"github.com/takara2314/claude-3.5-sonnet-demo-on-bedrock/internal/rag"
+)
+
+func main() {
+	endpoint := flag.String("endpoint", "", "OpenSearchのエンドポイント（例: https://search-xxx.us-east-1.es.amazonaws.com）")
+	indexName := flag.String("index", "documents", "投入先のOpenSearchインデックス名")
+	embedModelID := flag.String("embed-model", "amazon.titan-embed-text-v2:0", "埋め込みに使うBedrockモデルID")
+	tokenWindow := flag.Int("chunk-size", 200, "チャンクあたりの単語数")
+	overlap := flag.Int("chunk-overlap", 20, "チャンク間で重複させる単語数")
+	dims := flag.Int("dims", 1024, "埋め込みベクトルの次元数")
+	flag.Parse()
+
+	if *endpoint == "" {
+		log.Fatal("-endpoint は必須です")
+	}
+	if flag.NArg() == 0 {
+		log.Fatal("索引付けするファイルを1つ以上指定してください")
+	}
+
+	ctx := context.Background()
+
+	cfg, err := config.LoadDefaultConfig(ctx, config.WithRegion("us-east-1"))
+	if err != nil {
+		log.Fatalf("AWSの設定読み込みに失敗しました: %v", err)
+	}
+
+	client := bedrockruntime.NewFromConfig(cfg)
+	embedder := rag.NewEmbeddingClient(client, *embedModelID)
+	index := rag.NewIndex(cfg, *endpoint, *indexName, *dims)
+
+	if err := index.EnsureIndex(ctx); err != nil {
+		log.Fatalf("インデックスの作成に失敗しました: %v", err)
+	}
+
+	for _, path := range flag.Args() {
+		if err := ingestFile(ctx, embedder, index, path, *tokenWindow, *overlap); err != nil {
+			log.Fatalf("%s の取り込みに失敗しました: %v", path, err)
+		}
+	}
+}
+
+// ingestFile は1つのファイルをチャンク分割・埋め込みし、OpenSearchへ一括登録する
+func ingestFile(ctx context.Context, embedder *rag.EmbeddingClient, index *rag.Index, path string, tokenWindow, overlap int) error {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return fmt.Errorf("ファイルの読み込みに失敗しました: %w", err)
+	}
+
+	source := filepath.Base(path)
+	chunks := rag.ChunkText(string(data), source, tokenWindow, overlap)
+
+	docs := make([]rag.Document, 0, len(chunks))
+	for _, chunk := range chunks {
+		vector, err := embedder.Embed(ctx, chunk.Text)
+		if err != nil {
+			return fmt.Errorf("埋め込みの生成に失敗しました: %w", err)
+		}
+		docs = append(docs, rag.Document{
+			Text:   chunk.Text,
+			Source: chunk.Source,
+			Vector: vector,
+		})
+	}
+
+	if err := index.BulkIndex(ctx, docs); err != nil {
+		return fmt.Errorf("索引付けに失敗しました: %w", err)
+	}
+
+	fmt.Printf("%s: %d件のチャンクを索引付けしました\n", source, len(docs))
+	return nil
+}
@@ -0,0 +1,72 @@
+package main
+
+import (
+	"fmt"
+	"net/http"
+	"os"
+	"path/filepath"
+	"strings"
+)
+
+// maxImageBytes はBedrock Claude 3に渡せる画像1枚あたりの最大サイズ
+const maxImageBytes = 5 * 1024 * 1024
+
+// maxImagesPerRequest はBedrock Claude 3に渡せる1リクエストあたりの最大画像数
+const maxImagesPerRequest = 20
+
+// imagePaths は -image フラグを複数回指定できるようにするための flag.Value 実装
+type imagePaths []string
+
+func (p *imagePaths) String() string {
+	return strings.Join(*p, ",")
+}
+
+func (p *imagePaths) Set(value string) error {
+	*p = append(*p, value)
+	return nil
+}
+
+// extensionMediaTypes はファイル拡張子からメディアタイプを判定するための対応表
+var extensionMediaTypes = map[string]string{
+	".jpg":  "image/jpeg",
+	".jpeg": "image/jpeg",
+	".png":  "image/png",
+	".gif":  "image/gif",
+	".webp": "image/webp",
+}
+
+// loadImageAttachments は画像ファイルを読み込み、メディアタイプの判定とサイズ/枚数の検証を行った上で
+// ImageAttachmentのスライスを返す
+func loadImageAttachments(paths []string) ([]ImageAttachment, error) {
+	if len(paths) > maxImagesPerRequest {
+		return nil, fmt.Errorf("画像は1リクエストあたり最大%d枚までです（指定: %d枚）", maxImagesPerRequest, len(paths))
+	}
+
+	attachments := make([]ImageAttachment, 0, len(paths))
+	for _, path := range paths {
+		data, err := os.ReadFile(path)
+		if err != nil {
+			return nil, fmt.Errorf("画像ファイル %s の読み込みに失敗しました: %w", path, err)
+		}
+
+		if len(data) > maxImageBytes {
+			return nil, fmt.Errorf("画像 %s が大きすぎます（%dバイト、上限は%dバイト）", path, len(data), maxImageBytes)
+		}
+
+		mediaType := mediaTypeForImage(path, data)
+
+		attachments = append(attachments, ImageAttachment{MediaType: mediaType, Data: data})
+	}
+
+	return attachments, nil
+}
+
+// mediaTypeForImage はファイル拡張子からメディアタイプを判定し、未知の拡張子の場合は
+// ファイルの内容から http.DetectContentType で判定する
+func mediaTypeForImage(path string, data []byte) string {
+	ext := strings.ToLower(filepath.Ext(path))
+	if mediaType, ok := extensionMediaTypes[ext]; ok {
+		return mediaType
+	}
+	return http.DetectContentType(data)
+}
@@ -0,0 +1,26 @@
+package rag
+
+import "strings"
+
+// AugmentSystemPrompt は検索で取得したドキュメントを出典付きでシステムプロンプトの先頭に追加する
+func AugmentSystemPrompt(basePrompt string, docs []Document) string {
+	if len(docs) == 0 {
+		return basePrompt
+	}
+
+	var b strings.Builder
+	b.WriteString("以下は質問に関連する参考情報です。回答の際は出典を明示してください。\n\n")
+	for i, doc := range docs {
+		b.WriteString("[")
+		b.WriteString(doc.Source)
+		b.WriteString("] ")
+		b.WriteString(doc.Text)
+		if i != len(docs)-1 {
+			b.WriteString("\n\n")
+		}
+	}
+	b.WriteString("\n\n")
+	b.WriteString(basePrompt)
+
+	return b.String()
+}
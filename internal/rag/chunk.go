@@ -0,0 +1,43 @@
+// Package rag はBedrockの埋め込みモデルとOpenSearchを使ったRAG（Retrieval-Augmented Generation）を提供する
+package rag
+
+import "strings"
+
+// Chunk はRAGの索引対象となる1つのテキスト断片
+type Chunk struct {
+	Text   string
+	Source string
+}
+
+// ChunkText はテキストをtokenWindow語ごと（overlap語分重複させながら）のチャンクに分割する。
+// 簡易実装として「トークン」を空白区切りの単語として扱う
+func ChunkText(text, source string, tokenWindow, overlap int) []Chunk {
+	words := strings.Fields(text)
+	if len(words) == 0 {
+		return nil
+	}
+	if tokenWindow <= 0 {
+		tokenWindow = 200
+	}
+	if overlap < 0 || overlap >= tokenWindow {
+		overlap = 0
+	}
+
+	var chunks []Chunk
+	step := tokenWindow - overlap
+	for start := 0; start < len(words); start += step {
+		end := start + tokenWindow
+		if end > len(words) {
+			end = len(words)
+		}
+		chunks = append(chunks, Chunk{
+			Text:   strings.Join(words[start:end], " "),
+			Source: source,
+		})
+		if end == len(words) {
+			break
+		}
+	}
+
+	return chunks
+}
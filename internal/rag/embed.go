@@ -0,0 +1,56 @@
+package rag
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+
+	"github.com/aws/aws-sdk-go-v2/aws"
+	"github.com/aws/aws-sdk-go-v2/service/bedrockruntime"
+)
+
+// EmbeddingClient はBedrock上の埋め込みモデル（例: amazon.titan-embed-text-v2:0）を呼び出すクライアント
+type EmbeddingClient struct {
+	client  *bedrockruntime.Client
+	modelID string
+}
+
+// NewEmbeddingClient はEmbeddingClientを生成する
+func NewEmbeddingClient(client *bedrockruntime.Client, modelID string) *EmbeddingClient {
+	return &EmbeddingClient{client: client, modelID: modelID}
+}
+
+// titanEmbedRequest はTitan Embeddings系モデルへのリクエスト構造体
+type titanEmbedRequest struct {
+	InputText string `json:"inputText"`
+}
+
+// titanEmbedResponse はTitan Embeddings系モデルからのレスポンス構造体
+type titanEmbedResponse struct {
+	Embedding           []float64 `json:"embedding"`
+	InputTextTokenCount int       `json:"inputTextTokenCount"`
+}
+
+// Embed は1件のテキストをベクトルに変換する
+func (e *EmbeddingClient) Embed(ctx context.Context, text string) ([]float64, error) {
+	body, err := json.Marshal(titanEmbedRequest{InputText: text})
+	if err != nil {
+		return nil, fmt.Errorf("埋め込みリクエストのJSONエンコードに失敗しました: %w", err)
+	}
+
+	result, err := e.client.InvokeModel(ctx, &bedrockruntime.InvokeModelInput{
+		ModelId:     aws.String(e.modelID),
+		ContentType: aws.String("application/json"),
+		Body:        body,
+	})
+	if err != nil {
+		return nil, fmt.Errorf("埋め込みモデルの呼び出しに失敗しました: %w", err)
+	}
+
+	var resp titanEmbedResponse
+	if err := json.Unmarshal(result.Body, &resp); err != nil {
+		return nil, fmt.Errorf("埋め込みレスポンスの解析に失敗しました: %w", err)
+	}
+
+	return resp.Embedding, nil
+}
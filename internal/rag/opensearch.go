@@ -0,0 +1,226 @@
+package rag
+
+import (
+	"bytes"
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"strings"
+	"time"
+
+	"github.com/aws/aws-sdk-go-v2/aws"
+	v4signer "github.com/aws/aws-sdk-go-v2/aws/signer/v4"
+)
+
+// opensearchServiceName はSigV4署名で使うサービス名（マネージドAmazon OpenSearch Service向け）
+const opensearchServiceName = "es"
+
+// Document はOpenSearchに索引付けする1件のドキュメント
+type Document struct {
+	Text   string    `json:"text"`
+	Source string    `json:"source"`
+	Vector []float64 `json:"vector"`
+}
+
+// Index はOpenSearchのknn_vectorフィールドを持つ1つのインデックスへのハンドル
+type Index struct {
+	Endpoint string
+	Name     string
+	Dims     int
+	Region   string
+
+	httpClient  *http.Client
+	credentials aws.CredentialsProvider
+	signer      *v4signer.Signer
+}
+
+// NewIndex はIndexを生成する。cfgに含まれる認証情報でOpenSearchへのリクエストにSigV4署名を行うため、
+// マネージドAmazon OpenSearch ServiceのIAM認証に対応する
+func NewIndex(cfg aws.Config, endpoint, name string, dims int) *Index {
+	return &Index{
+		Endpoint:    strings.TrimRight(endpoint, "/"),
+		Name:        name,
+		Dims:        dims,
+		Region:      cfg.Region,
+		httpClient:  http.DefaultClient,
+		credentials: cfg.Credentials,
+		signer:      v4signer.NewSigner(),
+	}
+}
+
+// EnsureIndex はHNSWパラメータ付きのknn_vectorフィールドを持つインデックスが存在することを保証する
+func (idx *Index) EnsureIndex(ctx context.Context) error {
+	mapping := map[string]any{
+		"settings": map[string]any{
+			"index.knn": true,
+		},
+		"mappings": map[string]any{
+			"properties": map[string]any{
+				"text":   map[string]any{"type": "text"},
+				"source": map[string]any{"type": "keyword"},
+				"vector": map[string]any{
+					"type":      "knn_vector",
+					"dimension": idx.Dims,
+					"method": map[string]any{
+						"name":       "hnsw",
+						"space_type": "cosinesimil",
+						"engine":     "nmslib",
+					},
+				},
+			},
+		},
+	}
+
+	body, err := json.Marshal(mapping)
+	if err != nil {
+		return fmt.Errorf("インデックス定義のJSONエンコードに失敗しました: %w", err)
+	}
+
+	resp, err := idx.do(ctx, http.MethodPut, "/"+idx.Name, body)
+	if err != nil {
+		return err
+	}
+	defer resp.Body.Close()
+
+	// すでに存在する場合はそのまま成功扱いにする
+	if resp.StatusCode >= 300 && resp.StatusCode != http.StatusBadRequest {
+		return fmt.Errorf("インデックス作成に失敗しました: status=%d", resp.StatusCode)
+	}
+
+	return nil
+}
+
+// BulkIndex はドキュメントを_bulk APIで一括登録する
+func (idx *Index) BulkIndex(ctx context.Context, docs []Document) error {
+	var buf bytes.Buffer
+	for _, doc := range docs {
+		action := map[string]any{"index": map[string]any{"_index": idx.Name}}
+		actionLine, err := json.Marshal(action)
+		if err != nil {
+			return fmt.Errorf("bulkアクションのJSONエンコードに失敗しました: %w", err)
+		}
+		docLine, err := json.Marshal(doc)
+		if err != nil {
+			return fmt.Errorf("ドキュメントのJSONエンコードに失敗しました: %w", err)
+		}
+		buf.Write(actionLine)
+		buf.WriteByte('\n')
+		buf.Write(docLine)
+		buf.WriteByte('\n')
+	}
+
+	resp, err := idx.do(ctx, http.MethodPost, "/_bulk", buf.Bytes())
+	if err != nil {
+		return err
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode >= 300 {
+		respBody, _ := io.ReadAll(resp.Body)
+		return fmt.Errorf("bulk indexに失敗しました: status=%d, body=%s", resp.StatusCode, respBody)
+	}
+
+	return nil
+}
+
+// knnSearchRequest はknnクエリのリクエストボディ
+type knnSearchRequest struct {
+	Size  int `json:"size"`
+	Query struct {
+		KNN map[string]knnField `json:"knn"`
+	} `json:"query"`
+}
+
+type knnField struct {
+	Vector []float64 `json:"vector"`
+	K      int       `json:"k"`
+}
+
+type knnSearchResponse struct {
+	Hits struct {
+		Hits []struct {
+			Source Document `json:"_source"`
+		} `json:"hits"`
+	} `json:"hits"`
+}
+
+// Search はクエリベクトルに最も近いtopK件のドキュメントを返す
+func (idx *Index) Search(ctx context.Context, queryVector []float64, topK int) ([]Document, error) {
+	var req knnSearchRequest
+	req.Size = topK
+	req.Query.KNN = map[string]knnField{
+		"vector": {Vector: queryVector, K: topK},
+	}
+
+	body, err := json.Marshal(req)
+	if err != nil {
+		return nil, fmt.Errorf("検索クエリのJSONエンコードに失敗しました: %w", err)
+	}
+
+	resp, err := idx.do(ctx, http.MethodPost, "/"+idx.Name+"/_search", body)
+	if err != nil {
+		return nil, err
+	}
+	defer resp.Body.Close()
+
+	respBody, err := io.ReadAll(resp.Body)
+	if err != nil {
+		return nil, fmt.Errorf("検索レスポンスの読み込みに失敗しました: %w", err)
+	}
+	if resp.StatusCode >= 300 {
+		return nil, fmt.Errorf("knn検索に失敗しました: status=%d, body=%s", resp.StatusCode, respBody)
+	}
+
+	var searchResp knnSearchResponse
+	if err := json.Unmarshal(respBody, &searchResp); err != nil {
+		return nil, fmt.Errorf("検索レスポンスの解析に失敗しました: %w", err)
+	}
+
+	docs := make([]Document, 0, len(searchResp.Hits.Hits))
+	for _, hit := range searchResp.Hits.Hits {
+		docs = append(docs, hit.Source)
+	}
+
+	return docs, nil
+}
+
+func (idx *Index) do(ctx context.Context, method, path string, body []byte) (*http.Response, error) {
+	req, err := http.NewRequestWithContext(ctx, method, idx.Endpoint+path, bytes.NewReader(body))
+	if err != nil {
+		return nil, fmt.Errorf("OpenSearchリクエストの作成に失敗しました: %w", err)
+	}
+	req.Header.Set("Content-Type", "application/json")
+
+	if err := idx.sign(ctx, req, body); err != nil {
+		return nil, err
+	}
+
+	resp, err := idx.httpClient.Do(req)
+	if err != nil {
+		return nil, fmt.Errorf("OpenSearchへのリクエストに失敗しました: %w", err)
+	}
+
+	return resp, nil
+}
+
+// sign はリクエストにAWS SigV4署名を付与する。マネージドOpenSearch ServiceはIAM認証必須のため、
+// これがないと全リクエストが403になる
+func (idx *Index) sign(ctx context.Context, req *http.Request, body []byte) error {
+	creds, err := idx.credentials.Retrieve(ctx)
+	if err != nil {
+		return fmt.Errorf("AWS認証情報の取得に失敗しました: %w", err)
+	}
+
+	hash := sha256.Sum256(body)
+	payloadHash := hex.EncodeToString(hash[:])
+
+	if err := idx.signer.SignHTTP(ctx, creds, req, payloadHash, opensearchServiceName, idx.Region, time.Now()); err != nil {
+		return fmt.Errorf("OpenSearchリクエストの署名に失敗しました: %w", err)
+	}
+
+	return nil
+}
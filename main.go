@@ -1,8 +1,11 @@
 package main
 
 import (
+	"bufio"
 	"context"
 	"encoding/json"
+	"errors"
+	"flag"
 	"fmt"
 	"log"
 	"os"
@@ -11,46 +14,47 @@ import (
 	"github.com/aws/aws-sdk-go-v2/aws"
 	"github.com/aws/aws-sdk-go-v2/config"
 	"github.com/aws/aws-sdk-go-v2/service/bedrockruntime"
+	"github.com/aws/aws-sdk-go-v2/service/bedrockruntime/types"
+
+	"github.com/takara2314/claude-3.5-sonnet-demo-on-bedrock/internal/rag"
 )
 
-// ClaudeRequest はClaudeモデルへのリクエスト構造体
-type ClaudeRequest struct {
-	AnthropicVersion string                 `json:"anthropic_version"`
-	MaxTokens        int                    `json:"max_tokens"`
-	System           string                 `json:"system"`
-	Messages         []ClaudeRequestMessage `json:"messages"`
-}
+// defaultMaxHistoryMessages は会話履歴として保持するメッセージ数のデフォルト値
+const defaultMaxHistoryMessages = 20
 
-// ClaudeRequestMessage はClaudeリクエストのメッセージ構造体
-type ClaudeRequestMessage struct {
-	Role    string              `json:"role"`
-	Content []ClaudeTextContent `json:"content"`
-}
+// defaultModelID はデフォルトで使用するモデルID
+const defaultModelID = "anthropic.claude-3-5-sonnet-20240620-v1:0"
 
-// ClaudeTextContent はテキストコンテンツの構造体
-type ClaudeTextContent struct {
-	Type string `json:"type"`
-	Text string `json:"text"`
-}
+func main() {
+	modelID := flag.String("model", defaultModelID, "呼び出すBedrockモデルのID（プレフィックスでプロバイダを判定）")
+	stream := flag.Bool("stream", false, "InvokeModelWithResponseStreamを使ってストリーミングで応答を表示する")
+	maxHistory := flag.Int("max-history", defaultMaxHistoryMessages, "会話履歴として保持する最大メッセージ数")
+	tools := flag.Bool("tools", false, "get_weather/get_timeツールを使ったツール呼び出しデモを実行する（Claude専用）")
+	ragEndpoint := flag.String("rag-endpoint", "", "検索拡張生成（RAG）に使うOpenSearchのエンドポイント。空の場合はRAGを無効化する")
+	ragIndex := flag.String("rag-index", "documents", "RAGで検索するOpenSearchインデックス名")
+	ragTopK := flag.Int("rag-top-k", 3, "RAGで取得する関連文書の件数")
+	embedModelID := flag.String("embed-model", "amazon.titan-embed-text-v2:0", "RAGのクエリ埋め込みに使うBedrockモデルID")
+	var imageFlags imagePaths
+	flag.Var(&imageFlags, "image", "最初の発言に添付する画像ファイルのパス（複数回指定可）")
+	flag.Parse()
 
-// ClaudeUsage はトークン使用量の構造体
-type ClaudeUsage struct {
-	InputTokens  int `json:"input_tokens"`
-	OutputTokens int `json:"output_tokens"`
-}
+	var initialImages []ImageAttachment
+	if len(imageFlags) > 0 {
+		if !strings.Contains(*modelID, "claude-3") {
+			log.Fatalf("エラー: モデル \"%s\" はvision（画像入力）に対応していません。Claude 3系のモデルを指定してください", *modelID)
+		}
+		images, err := loadImageAttachments(imageFlags)
+		if err != nil {
+			log.Fatalf("画像の読み込みに失敗しました: %v", err)
+		}
+		initialImages = images
+	}
 
-// ClaudeResponse はClaudeモデルからのレスポンス構造体
-type ClaudeResponse struct {
-	ID           string              `json:"id"`
-	Type         string              `json:"type"`
-	Role         string              `json:"role"`
-	Content      []ClaudeTextContent `json:"content"`
-	StopReason   string              `json:"stop_reason"`
-	StopSequence string              `json:"stop_sequence"`
-	Usage        ClaudeUsage         `json:"usage"`
-}
+	provider, err := NewProvider(*modelID)
+	if err != nil {
+		log.Fatalf("プロバイダの判定に失敗しました: %v", err)
+	}
 
-func main() {
 	// AWSの設定を読み込む
 	region := "us-east-1"
 	cfg, err := config.LoadDefaultConfig(context.Background(), config.WithRegion(region))
@@ -61,65 +65,309 @@ func main() {
 	// Bedrockクライアントを作成
 	client := bedrockruntime.NewFromConfig(cfg)
 
-	// Claudeモデルの設定
-	modelID := "anthropic.claude-3-5-sonnet-20240620-v1:0"
-
-	// リクエストの作成
-	request := ClaudeRequest{
-		AnthropicVersion: "bedrock-2023-05-31",
-		MaxTokens:        1024,
-		System:           "幼稚園児を演じてください。",
-		Messages: []ClaudeRequestMessage{
-			{
-				Role: "user",
-				Content: []ClaudeTextContent{
-					{
-						Type: "text",
-						Text: "タイの首都は？",
-					},
-				},
-			},
-		},
-	}
-
-	// リクエストのJSONエンコード
-	body, err := json.Marshal(request)
+	if *tools {
+		if !strings.Contains(*modelID, "claude-3") {
+			log.Fatalf("エラー: モデル \"%s\" はツール呼び出しに対応していません。Claude 3系のモデルを指定してください", *modelID)
+		}
+		runToolDemo(client, *modelID)
+		return
+	}
+
+	conv := &Conversation{
+		System:   "幼稚園児を演じてください。",
+		Messages: []Message{},
+	}
+
+	var retriever *ragRetriever
+	if *ragEndpoint != "" {
+		retriever = &ragRetriever{
+			embedder: rag.NewEmbeddingClient(client, *embedModelID),
+			index:    rag.NewIndex(cfg, *ragEndpoint, *ragIndex, 1024),
+			topK:     *ragTopK,
+		}
+	}
+
+	runREPL(client, provider, *modelID, conv, *stream, *maxHistory, retriever, initialImages)
+}
+
+// ragRetriever はユーザーの発言をベクトル化してOpenSearchから関連文書を取得する
+type ragRetriever struct {
+	embedder *rag.EmbeddingClient
+	index    *rag.Index
+	topK     int
+}
+
+// augmentSystemPrompt はクエリに関連する文書を検索し、システムプロンプトへ出典付きで追加する
+func (r *ragRetriever) augmentSystemPrompt(ctx context.Context, system, query string) (string, error) {
+	vector, err := r.embedder.Embed(ctx, query)
+	if err != nil {
+		return "", fmt.Errorf("クエリの埋め込みに失敗しました: %w", err)
+	}
+
+	docs, err := r.index.Search(ctx, vector, r.topK)
 	if err != nil {
-		log.Fatalf("リクエストのJSONエンコードに失敗しました: %v", err)
+		return "", fmt.Errorf("関連文書の検索に失敗しました: %w", err)
+	}
+
+	return rag.AugmentSystemPrompt(system, docs), nil
+}
+
+// Conversation はシステムプロンプトと会話履歴をまとめた、プロバイダに依存しない状態
+type Conversation struct {
+	System   string    `json:"system"`
+	Messages []Message `json:"messages"`
+}
+
+// runREPL は標準入力から行を読み取りながらモデルと対話を続けるチャットループ
+func runREPL(client *bedrockruntime.Client, provider Provider, modelID string, conv *Conversation, stream bool, maxHistory int, retriever *ragRetriever, initialImages []ImageAttachment) {
+	var cumulativeUsage Usage
+
+	scanner := bufio.NewScanner(os.Stdin)
+	fmt.Println("モデルとの対話を開始します。終了するには Ctrl+D を入力してください。")
+
+	for {
+		fmt.Print("あなた> ")
+		if !scanner.Scan() {
+			break
+		}
+
+		line := strings.TrimSpace(scanner.Text())
+		if line == "" {
+			continue
+		}
+
+		if strings.HasPrefix(line, "/") {
+			handleSlashCommand(line, conv, &cumulativeUsage)
+			continue
+		}
+
+		userMessage := Message{Role: "user", Text: line}
+		if len(initialImages) > 0 {
+			userMessage.Images = initialImages
+			initialImages = nil
+		}
+		conv.Messages = append(conv.Messages, userMessage)
+		trimHistory(conv, maxHistory)
+
+		system := conv.System
+		if retriever != nil {
+			augmented, err := retriever.augmentSystemPrompt(context.Background(), conv.System, line)
+			if err != nil {
+				fmt.Printf("エラー: RAG検索に失敗しました: %v\n", err)
+			} else {
+				system = augmented
+			}
+		}
+
+		body, err := provider.BuildRequest(system, conv.Messages, GenOptions{MaxTokens: 1024})
+		if err != nil {
+			log.Fatalf("リクエストの組み立てに失敗しました: %v", err)
+		}
+
+		var replyText string
+		if stream {
+			text, usage, err := invokeModelStream(client, provider, modelID, body)
+			if err != nil {
+				handleInvokeError(err, modelID)
+				continue
+			}
+			replyText = text
+			cumulativeUsage.InputTokens += usage.InputTokens
+			cumulativeUsage.OutputTokens += usage.OutputTokens
+		} else {
+			reply, err := invokeModel(client, provider, modelID, body)
+			if err != nil {
+				handleInvokeError(err, modelID)
+				continue
+			}
+			replyText = reply.Text
+			cumulativeUsage.InputTokens += reply.Usage.InputTokens
+			cumulativeUsage.OutputTokens += reply.Usage.OutputTokens
+			fmt.Println(replyText)
+		}
+
+		conv.Messages = append(conv.Messages, Message{Role: "assistant", Text: replyText})
+		trimHistory(conv, maxHistory)
+	}
+}
+
+// handleSlashCommand はREPL中のスラッシュコマンド（/system, /reset, /save, /load, /tokens）を処理する
+func handleSlashCommand(line string, conv *Conversation, cumulativeUsage *Usage) {
+	fields := strings.SplitN(line, " ", 2)
+	command := fields[0]
+	var arg string
+	if len(fields) > 1 {
+		arg = strings.TrimSpace(fields[1])
+	}
+
+	switch command {
+	case "/system":
+		if arg == "" {
+			fmt.Println("使い方: /system <システムプロンプト>")
+			return
+		}
+		conv.System = arg
+		fmt.Println("システムプロンプトを更新しました")
+	case "/reset":
+		conv.Messages = []Message{}
+		fmt.Println("会話履歴をリセットしました")
+	case "/save":
+		if arg == "" {
+			fmt.Println("使い方: /save <ファイル名>")
+			return
+		}
+		data, err := json.MarshalIndent(conv, "", "  ")
+		if err != nil {
+			fmt.Printf("エラー: 会話のJSONエンコードに失敗しました: %v\n", err)
+			return
+		}
+		if err := os.WriteFile(arg, data, 0644); err != nil {
+			fmt.Printf("エラー: ファイルへの書き込みに失敗しました: %v\n", err)
+			return
+		}
+		fmt.Printf("会話を %s に保存しました\n", arg)
+	case "/load":
+		if arg == "" {
+			fmt.Println("使い方: /load <ファイル名>")
+			return
+		}
+		data, err := os.ReadFile(arg)
+		if err != nil {
+			fmt.Printf("エラー: ファイルの読み込みに失敗しました: %v\n", err)
+			return
+		}
+		var loaded Conversation
+		if err := json.Unmarshal(data, &loaded); err != nil {
+			fmt.Printf("エラー: 会話のJSON解析に失敗しました: %v\n", err)
+			return
+		}
+		*conv = loaded
+		fmt.Printf("%s から会話を読み込みました\n", arg)
+	case "/tokens":
+		fmt.Printf("累計トークン使用量: 入力=%d, 出力=%d\n", cumulativeUsage.InputTokens, cumulativeUsage.OutputTokens)
+	default:
+		fmt.Printf("不明なコマンドです: %s\n", command)
+	}
+}
+
+// trimHistory はメッセージ数がmaxHistoryを超えた場合に古いメッセージから削除する。
+// Claudeは先頭メッセージがuserであることを要求するため、切り詰め後の先頭がassistantに
+// なってしまう場合はそのメッセージも一緒に捨てて、userから始まるように揃える
+func trimHistory(conv *Conversation, maxHistory int) {
+	if maxHistory <= 0 || len(conv.Messages) <= maxHistory {
+		return
 	}
+	conv.Messages = conv.Messages[len(conv.Messages)-maxHistory:]
+	if len(conv.Messages) > 0 && conv.Messages[0].Role != "user" {
+		conv.Messages = conv.Messages[1:]
+	}
+}
 
-	// モデルの呼び出し
-	result, err := client.InvokeModel(context.Background(), &bedrockruntime.InvokeModelInput{
-		ModelId:     aws.String(modelID),
-		ContentType: aws.String("application/json"),
-		Body:        body,
+// invokeModel はリトライ付きでInvokeModelを呼び出し、プロバイダ共通のReplyを返す
+func invokeModel(client *bedrockruntime.Client, provider Provider, modelID string, body []byte) (Reply, error) {
+	ctx := context.Background()
+
+	var result *bedrockruntime.InvokeModelOutput
+	err := withRetry(ctx, defaultRetryConfig, func() error {
+		var err error
+		result, err = client.InvokeModel(ctx, &bedrockruntime.InvokeModelInput{
+			ModelId:     aws.String(modelID),
+			ContentType: aws.String("application/json"),
+			Body:        body,
+		})
+		return err
 	})
+	if err != nil {
+		return Reply{}, err
+	}
+
+	return provider.ParseResponse(result.Body)
+}
+
+// invokeModelStream はリトライ付きでInvokeModelWithResponseStreamを呼び出し、テキストデルタを逐次出力
+// しつつ、最終的な応答全文とトークン使用量を返す
+func invokeModelStream(client *bedrockruntime.Client, provider Provider, modelID string, body []byte) (string, Usage, error) {
+	ctx := context.Background()
 
-	// エラー処理
+	var output *bedrockruntime.InvokeModelWithResponseStreamOutput
+	err := withRetry(ctx, defaultRetryConfig, func() error {
+		var err error
+		output, err = client.InvokeModelWithResponseStream(ctx, &bedrockruntime.InvokeModelWithResponseStreamInput{
+			ModelId:     aws.String(modelID),
+			ContentType: aws.String("application/json"),
+			Body:        body,
+		})
+		return err
+	})
 	if err != nil {
-		handleInvokeError(err, modelID)
-		os.Exit(1)
+		return "", Usage{}, err
+	}
+
+	writer := bufio.NewWriter(os.Stdout)
+	defer writer.Flush()
+
+	var usage Usage
+	var fullText strings.Builder
+	stream := output.GetStream()
+	defer stream.Close()
+
+	for event := range stream.Events() {
+		chunk, ok := event.(*types.ResponseStreamMemberChunk)
+		if !ok {
+			continue
+		}
+
+		streamChunk, err := provider.ParseStreamChunk(chunk.Value.Bytes)
+		if err != nil {
+			return "", Usage{}, err
+		}
+
+		if streamChunk.TextDelta != "" {
+			fmt.Fprint(writer, streamChunk.TextDelta)
+			writer.Flush()
+			fullText.WriteString(streamChunk.TextDelta)
+		}
+		if streamChunk.Usage.InputTokens > 0 {
+			usage.InputTokens = streamChunk.Usage.InputTokens
+		}
+		if streamChunk.Usage.OutputTokens > 0 {
+			usage.OutputTokens = streamChunk.Usage.OutputTokens
+		}
 	}
 
-	// レスポンスの解析
-	var response ClaudeResponse
-	if err := json.Unmarshal(result.Body, &response); err != nil {
-		log.Fatalf("レスポンスの解析に失敗しました: %v", err)
+	if err := stream.Err(); err != nil {
+		return "", Usage{}, err
 	}
 
-	// 結果の表示
-	fmt.Println(response.Content[0].Text)
+	fmt.Println()
+	return fullText.String(), usage, nil
 }
 
-// handleInvokeError はモデル呼び出し時のエラーを処理する関数
+// handleInvokeError はモデル呼び出し時のエラーを、AWS SDKの型付きBedrockエラーを見て処理する関数
 func handleInvokeError(err error, modelID string) {
-	errMsg := err.Error()
+	var validation *types.ValidationException
+	var accessDenied *types.AccessDeniedException
+	var throttling *types.ThrottlingException
+	var modelTimeout *types.ModelTimeoutException
+	var modelNotReady *types.ModelNotReadyException
+	var serviceQuota *types.ServiceQuotaExceededException
+
 	switch {
-	case strings.Contains(errMsg, "no such host"):
+	case errors.As(err, &validation):
+		fmt.Printf("エラー: リクエストが不正です: %v\n", err)
+	case errors.As(err, &accessDenied):
+		fmt.Printf("エラー: モデル \"%s\" へのアクセスが拒否されました。AWSコンソールのモデルアクセス設定 (https://console.aws.amazon.com/bedrock/home#/modelaccess) でアクセスをリクエストしてください\n", modelID)
+	case errors.As(err, &throttling):
+		fmt.Printf("エラー: リクエストがスロットリングされ、リトライ上限に達しました: %v\n", err)
+	case errors.As(err, &modelTimeout):
+		fmt.Printf("エラー: モデルの呼び出しがタイムアウトし、リトライ上限に達しました: %v\n", err)
+	case errors.As(err, &modelNotReady):
+		fmt.Printf("エラー: モデル \"%s\" の準備ができておらず、リトライ上限に達しました: %v\n", modelID, err)
+	case errors.As(err, &serviceQuota):
+		fmt.Printf("エラー: サービスクォータを超過しました: %v\n", err)
+	case strings.Contains(err.Error(), "no such host"):
 		fmt.Printf("エラー: 選択されたリージョンでBedrockサービスが利用できません。リージョンごとのサービス提供状況を https://aws.amazon.com/about-aws/global-infrastructure/regional-product-services/ で確認してください\n")
-	case strings.Contains(errMsg, "Could not resolve the foundation model"):
-		fmt.Printf("エラー: モデル識別子 \"%s\" からファンデーションモデルを解決できませんでした。指定されたモデルが存在し、指定されたリージョンでアクセス可能であることを確認してください\n", modelID)
 	default:
-		fmt.Printf("エラー: Anthropic Claudeの呼び出しに失敗しました: %v\n", err)
+		fmt.Printf("エラー: モデルの呼び出しに失敗しました: %v\n", err)
 	}
 }
@@ -0,0 +1,83 @@
+package main
+
+import (
+	"fmt"
+	"strings"
+)
+
+// Message はプロバイダに依存しない会話メッセージ
+type Message struct {
+	Role   string            `json:"role"`
+	Text   string            `json:"text"`
+	Images []ImageAttachment `json:"images,omitempty"`
+}
+
+// ImageAttachment はユーザーメッセージに添付する画像1枚分のデータ
+type ImageAttachment struct {
+	MediaType string `json:"media_type"`
+	Data      []byte `json:"data"`
+}
+
+// hasImages はメッセージ列の中に画像添付を含むものがあるかどうかを返す
+func hasImages(messages []Message) bool {
+	for _, m := range messages {
+		if len(m.Images) > 0 {
+			return true
+		}
+	}
+	return false
+}
+
+// errVisionNotSupported はvision非対応のモデル/プロバイダに画像を渡した場合のエラー
+var errVisionNotSupported = fmt.Errorf("このモデルは画像入力（vision）に対応していません")
+
+// GenOptions はモデル呼び出し時の共通オプション
+type GenOptions struct {
+	MaxTokens int
+}
+
+// Usage はプロバイダに依存しないトークン使用量
+type Usage struct {
+	InputTokens  int
+	OutputTokens int
+}
+
+// Reply はモデル呼び出しの共通レスポンス
+type Reply struct {
+	Text       string
+	StopReason string
+	Usage      Usage
+}
+
+// StreamChunk はストリーミング応答の1イベントを表す共通構造体
+type StreamChunk struct {
+	TextDelta string
+	Done      bool
+	Usage     Usage
+}
+
+// Provider はBedrock上の各モデルファミリのリクエスト/レスポンス形式を抽象化するインターフェース
+type Provider interface {
+	// BuildRequest はシステムプロンプトと会話履歴からモデル呼び出し用のリクエストボディを組み立てる
+	BuildRequest(system string, messages []Message, opts GenOptions) ([]byte, error)
+	// ParseResponse はInvokeModelのレスポンスボディを共通のReplyに変換する
+	ParseResponse(body []byte) (Reply, error)
+	// ParseStreamChunk はInvokeModelWithResponseStreamの1イベントを共通のStreamChunkに変換する
+	ParseStreamChunk(chunk []byte) (StreamChunk, error)
+}
+
+// NewProvider はモデルIDのプレフィックスから対応するProviderを返す
+func NewProvider(modelID string) (Provider, error) {
+	switch {
+	case strings.HasPrefix(modelID, "anthropic."):
+		return ClaudeProvider{}, nil
+	case strings.HasPrefix(modelID, "amazon.titan-"):
+		return TitanProvider{}, nil
+	case strings.HasPrefix(modelID, "meta.llama"):
+		return LlamaProvider{}, nil
+	case strings.HasPrefix(modelID, "mistral."):
+		return MistralProvider{}, nil
+	default:
+		return nil, fmt.Errorf("未対応のモデルIDです: %s", modelID)
+	}
+}
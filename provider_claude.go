@@ -0,0 +1,164 @@
+package main
+
+import (
+	"encoding/base64"
+	"encoding/json"
+)
+
+// ClaudeRequest はClaudeモデルへのリクエスト構造体
+type ClaudeRequest struct {
+	AnthropicVersion string                 `json:"anthropic_version"`
+	MaxTokens        int                    `json:"max_tokens"`
+	System           string                 `json:"system"`
+	Messages         []ClaudeRequestMessage `json:"messages"`
+	Tools            []ClaudeTool           `json:"tools,omitempty"`
+}
+
+// ClaudeRequestMessage はClaudeリクエストのメッセージ構造体
+type ClaudeRequestMessage struct {
+	Role    string              `json:"role"`
+	Content []ClaudeTextContent `json:"content"`
+}
+
+// ClaudeTool はClaudeに渡すツール定義（名前・説明・JSON Schema）
+type ClaudeTool struct {
+	Name        string          `json:"name"`
+	Description string          `json:"description"`
+	InputSchema json.RawMessage `json:"input_schema"`
+}
+
+// ClaudeTextContent はClaudeのコンテンツブロック。type に応じて text / image / tool_use / tool_result の
+// いずれかとして使われるタグ付きユニオン
+type ClaudeTextContent struct {
+	Type string `json:"type"`
+
+	// text ブロック用
+	Text string `json:"text,omitempty"`
+
+	// image ブロック用
+	Source *ClaudeImageSource `json:"source,omitempty"`
+
+	// tool_use ブロック用
+	ID    string          `json:"id,omitempty"`
+	Name  string          `json:"name,omitempty"`
+	Input json.RawMessage `json:"input,omitempty"`
+
+	// tool_result ブロック用
+	ToolUseID string `json:"tool_use_id,omitempty"`
+	Content   string `json:"content,omitempty"`
+}
+
+// ClaudeImageSource はimageブロックの中身（Base64エンコードされた画像データ）
+type ClaudeImageSource struct {
+	Type      string `json:"type"`
+	MediaType string `json:"media_type"`
+	Data      string `json:"data"`
+}
+
+// ClaudeUsage はトークン使用量の構造体
+type ClaudeUsage struct {
+	InputTokens  int `json:"input_tokens"`
+	OutputTokens int `json:"output_tokens"`
+}
+
+// ClaudeResponse はClaudeモデルからのレスポンス構造体
+type ClaudeResponse struct {
+	ID           string              `json:"id"`
+	Type         string              `json:"type"`
+	Role         string              `json:"role"`
+	Content      []ClaudeTextContent `json:"content"`
+	StopReason   string              `json:"stop_reason"`
+	StopSequence string              `json:"stop_sequence"`
+	Usage        ClaudeUsage         `json:"usage"`
+}
+
+// ClaudeStreamEvent はInvokeModelWithResponseStreamが返すイベントの共通構造体
+type ClaudeStreamEvent struct {
+	Type  string `json:"type"`
+	Index int    `json:"index"`
+	Delta struct {
+		Type         string `json:"type"`
+		Text         string `json:"text"`
+		StopReason   string `json:"stop_reason"`
+		StopSequence string `json:"stop_sequence"`
+	} `json:"delta"`
+	Usage ClaudeUsage `json:"usage"`
+
+	// message_start イベント用。入力トークン数はここにしか含まれない
+	Message struct {
+		Usage ClaudeUsage `json:"usage"`
+	} `json:"message"`
+}
+
+// ClaudeProvider はAnthropic Claude (Messages API) 向けのProvider実装
+type ClaudeProvider struct{}
+
+// BuildRequest はClaude Messages API形式のリクエストボディを組み立てる
+func (ClaudeProvider) BuildRequest(system string, messages []Message, opts GenOptions) ([]byte, error) {
+	req := ClaudeRequest{
+		AnthropicVersion: "bedrock-2023-05-31",
+		MaxTokens:        opts.MaxTokens,
+		System:           system,
+		Messages:         make([]ClaudeRequestMessage, 0, len(messages)),
+	}
+	for _, m := range messages {
+		var content []ClaudeTextContent
+		for _, img := range m.Images {
+			content = append(content, ClaudeTextContent{
+				Type: "image",
+				Source: &ClaudeImageSource{
+					Type:      "base64",
+					MediaType: img.MediaType,
+					Data:      base64.StdEncoding.EncodeToString(img.Data),
+				},
+			})
+		}
+		if m.Text != "" {
+			content = append(content, ClaudeTextContent{Type: "text", Text: m.Text})
+		}
+		req.Messages = append(req.Messages, ClaudeRequestMessage{Role: m.Role, Content: content})
+	}
+	return json.Marshal(req)
+}
+
+// ParseResponse はClaudeのレスポンスを共通のReplyに変換する
+func (ClaudeProvider) ParseResponse(body []byte) (Reply, error) {
+	var resp ClaudeResponse
+	if err := json.Unmarshal(body, &resp); err != nil {
+		return Reply{}, err
+	}
+
+	var text string
+	if len(resp.Content) > 0 {
+		text = resp.Content[0].Text
+	}
+
+	return Reply{
+		Text:       text,
+		StopReason: resp.StopReason,
+		Usage:      Usage{InputTokens: resp.Usage.InputTokens, OutputTokens: resp.Usage.OutputTokens},
+	}, nil
+}
+
+// ParseStreamChunk はClaudeのイベントストリームの1イベントを共通のStreamChunkに変換する
+func (ClaudeProvider) ParseStreamChunk(chunk []byte) (StreamChunk, error) {
+	var event ClaudeStreamEvent
+	if err := json.Unmarshal(chunk, &event); err != nil {
+		return StreamChunk{}, err
+	}
+
+	switch event.Type {
+	case "message_start":
+		return StreamChunk{Usage: Usage{InputTokens: event.Message.Usage.InputTokens}}, nil
+	case "content_block_delta":
+		if event.Delta.Type == "text_delta" {
+			return StreamChunk{TextDelta: event.Delta.Text}, nil
+		}
+	case "message_delta":
+		return StreamChunk{Usage: Usage{OutputTokens: event.Usage.OutputTokens}}, nil
+	case "message_stop":
+		return StreamChunk{Done: true}, nil
+	}
+
+	return StreamChunk{}, nil
+}
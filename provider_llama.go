@@ -0,0 +1,88 @@
+package main
+
+import "encoding/json"
+
+// LlamaRequest はMeta Llama 3モデルへのリクエスト構造体
+type LlamaRequest struct {
+	Prompt      string  `json:"prompt"`
+	MaxGenLen   int     `json:"max_gen_len"`
+	Temperature float64 `json:"temperature"`
+	TopP        float64 `json:"top_p"`
+}
+
+// LlamaResponse はLlama 3モデルからのレスポンス構造体
+type LlamaResponse struct {
+	Generation           string `json:"generation"`
+	PromptTokenCount     int    `json:"prompt_token_count"`
+	GenerationTokenCount int    `json:"generation_token_count"`
+	StopReason           string `json:"stop_reason"`
+}
+
+// LlamaStreamChunk はLlama 3のストリーミング応答の1チャンク
+type LlamaStreamChunk struct {
+	Generation           string `json:"generation"`
+	GenerationTokenCount int    `json:"generation_token_count"`
+	StopReason           string `json:"stop_reason"`
+}
+
+// LlamaProvider はMeta Llama 3向けのProvider実装
+type LlamaProvider struct{}
+
+// BuildRequest はLlama 3のチャットプロンプトテンプレートに沿ってリクエストを組み立てる
+func (LlamaProvider) BuildRequest(system string, messages []Message, opts GenOptions) ([]byte, error) {
+	if hasImages(messages) {
+		return nil, errVisionNotSupported
+	}
+
+	req := LlamaRequest{
+		Prompt:      buildLlamaPrompt(system, messages),
+		MaxGenLen:   opts.MaxTokens,
+		Temperature: 0.7,
+		TopP:        0.9,
+	}
+	return json.Marshal(req)
+}
+
+// ParseResponse はLlama 3のレスポンスを共通のReplyに変換する
+func (LlamaProvider) ParseResponse(body []byte) (Reply, error) {
+	var resp LlamaResponse
+	if err := json.Unmarshal(body, &resp); err != nil {
+		return Reply{}, err
+	}
+
+	return Reply{
+		Text:       resp.Generation,
+		StopReason: resp.StopReason,
+		Usage: Usage{
+			InputTokens:  resp.PromptTokenCount,
+			OutputTokens: resp.GenerationTokenCount,
+		},
+	}, nil
+}
+
+// ParseStreamChunk はLlama 3のストリーミングチャンクを共通のStreamChunkに変換する
+func (LlamaProvider) ParseStreamChunk(chunk []byte) (StreamChunk, error) {
+	var event LlamaStreamChunk
+	if err := json.Unmarshal(chunk, &event); err != nil {
+		return StreamChunk{}, err
+	}
+
+	return StreamChunk{
+		TextDelta: event.Generation,
+		Done:      event.StopReason != "",
+		Usage:     Usage{OutputTokens: event.GenerationTokenCount},
+	}, nil
+}
+
+// buildLlamaPrompt はLlama 3のチャットテンプレート（<|start_header_id|>...）に沿ってプロンプトを組み立てる
+func buildLlamaPrompt(system string, messages []Message) string {
+	prompt := "<|begin_of_text|>"
+	if system != "" {
+		prompt += "<|start_header_id|>system<|end_header_id|>\n\n" + system + "<|eot_id|>"
+	}
+	for _, m := range messages {
+		prompt += "<|start_header_id|>" + m.Role + "<|end_header_id|>\n\n" + m.Text + "<|eot_id|>"
+	}
+	prompt += "<|start_header_id|>assistant<|end_header_id|>\n\n"
+	return prompt
+}
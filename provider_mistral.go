@@ -0,0 +1,103 @@
+package main
+
+import (
+	"encoding/json"
+	"strings"
+)
+
+// MistralRequest はMistralモデルへのリクエスト構造体
+type MistralRequest struct {
+	Prompt      string  `json:"prompt"`
+	MaxTokens   int     `json:"max_tokens"`
+	Temperature float64 `json:"temperature"`
+	TopP        float64 `json:"top_p"`
+}
+
+// MistralOutput はMistralレスポンスに含まれる1件の生成結果
+type MistralOutput struct {
+	Text       string `json:"text"`
+	StopReason string `json:"stop_reason"`
+}
+
+// MistralResponse はMistralモデルからのレスポンス構造体
+type MistralResponse struct {
+	Outputs []MistralOutput `json:"outputs"`
+}
+
+// MistralProvider はMistral向けのProvider実装
+type MistralProvider struct{}
+
+// BuildRequest はMistralの[INST]プロンプトテンプレートに沿ってリクエストを組み立てる
+func (MistralProvider) BuildRequest(system string, messages []Message, opts GenOptions) ([]byte, error) {
+	if hasImages(messages) {
+		return nil, errVisionNotSupported
+	}
+
+	req := MistralRequest{
+		Prompt:      buildMistralPrompt(system, messages),
+		MaxTokens:   opts.MaxTokens,
+		Temperature: 0.7,
+		TopP:        0.9,
+	}
+	return json.Marshal(req)
+}
+
+// ParseResponse はMistralのレスポンスを共通のReplyに変換する
+func (MistralProvider) ParseResponse(body []byte) (Reply, error) {
+	var resp MistralResponse
+	if err := json.Unmarshal(body, &resp); err != nil {
+		return Reply{}, err
+	}
+
+	var text, stopReason string
+	if len(resp.Outputs) > 0 {
+		text = resp.Outputs[0].Text
+		stopReason = resp.Outputs[0].StopReason
+	}
+
+	return Reply{Text: text, StopReason: stopReason}, nil
+}
+
+// ParseStreamChunk はMistralのストリーミングチャンクを共通のStreamChunkに変換する
+func (MistralProvider) ParseStreamChunk(chunk []byte) (StreamChunk, error) {
+	var event MistralResponse
+	if err := json.Unmarshal(chunk, &event); err != nil {
+		return StreamChunk{}, err
+	}
+
+	if len(event.Outputs) == 0 {
+		return StreamChunk{}, nil
+	}
+
+	return StreamChunk{
+		TextDelta: event.Outputs[0].Text,
+		Done:      event.Outputs[0].StopReason != "",
+	}, nil
+}
+
+// buildMistralPrompt はMistralの[INST]プロンプトテンプレートに沿って、システムプロンプトと
+// 会話履歴（ユーザー発言とアシスタント応答の両方）からマルチターンのプロンプトを組み立てる
+func buildMistralPrompt(system string, messages []Message) string {
+	var b strings.Builder
+	b.WriteString("<s>")
+	firstUserTurn := true
+
+	for _, m := range messages {
+		switch m.Role {
+		case "assistant":
+			b.WriteString(m.Text)
+			b.WriteString("</s>")
+		default:
+			b.WriteString("[INST] ")
+			if firstUserTurn && system != "" {
+				b.WriteString(system)
+				b.WriteString("\n\n")
+			}
+			b.WriteString(m.Text)
+			b.WriteString(" [/INST]")
+			firstUserTurn = false
+		}
+	}
+
+	return b.String()
+}
@@ -0,0 +1,127 @@
+package main
+
+import (
+	"encoding/json"
+	"strings"
+)
+
+// TitanRequest はAmazon Titan Textモデルへのリクエスト構造体
+type TitanRequest struct {
+	InputText            string                    `json:"inputText"`
+	TextGenerationConfig TitanTextGenerationConfig `json:"textGenerationConfig"`
+}
+
+// TitanTextGenerationConfig はTitanの生成パラメータ
+type TitanTextGenerationConfig struct {
+	MaxTokenCount int     `json:"maxTokenCount"`
+	TopP          float64 `json:"topP"`
+	Temperature   float64 `json:"temperature"`
+}
+
+// TitanResult はTitanレスポンスに含まれる1件の生成結果
+type TitanResult struct {
+	TokenCount       int    `json:"tokenCount"`
+	OutputText       string `json:"outputText"`
+	CompletionReason string `json:"completionReason"`
+}
+
+// TitanResponse はTitanモデルからのレスポンス構造体
+type TitanResponse struct {
+	InputTextTokenCount int           `json:"inputTextTokenCount"`
+	Results             []TitanResult `json:"results"`
+}
+
+// TitanStreamChunk はTitanのストリーミング応答の1チャンク
+type TitanStreamChunk struct {
+	OutputText                string `json:"outputText"`
+	CompletionReason          string `json:"completionReason"`
+	TotalOutputTextTokenCount int    `json:"totalOutputTextTokenCount"`
+}
+
+// TitanProvider はAmazon Titan Text向けのProvider実装
+type TitanProvider struct{}
+
+// BuildRequest はシステムプロンプトと会話履歴を1つのinputTextにまとめてTitan形式のリクエストを組み立てる
+func (TitanProvider) BuildRequest(system string, messages []Message, opts GenOptions) ([]byte, error) {
+	if hasImages(messages) {
+		return nil, errVisionNotSupported
+	}
+
+	req := TitanRequest{
+		InputText: buildPlainTextPrompt(system, messages, "User", "Bot"),
+		TextGenerationConfig: TitanTextGenerationConfig{
+			MaxTokenCount: opts.MaxTokens,
+			TopP:          0.9,
+			Temperature:   0.7,
+		},
+	}
+	return json.Marshal(req)
+}
+
+// ParseResponse はTitanのレスポンスを共通のReplyに変換する
+func (TitanProvider) ParseResponse(body []byte) (Reply, error) {
+	var resp TitanResponse
+	if err := json.Unmarshal(body, &resp); err != nil {
+		return Reply{}, err
+	}
+
+	var text, stopReason string
+	if len(resp.Results) > 0 {
+		text = resp.Results[0].OutputText
+		stopReason = resp.Results[0].CompletionReason
+	}
+
+	return Reply{
+		Text:       text,
+		StopReason: stopReason,
+		Usage: Usage{
+			InputTokens:  resp.InputTextTokenCount,
+			OutputTokens: sumTitanOutputTokens(resp.Results),
+		},
+	}, nil
+}
+
+// ParseStreamChunk はTitanのストリーミングチャンクを共通のStreamChunkに変換する
+func (TitanProvider) ParseStreamChunk(chunk []byte) (StreamChunk, error) {
+	var event TitanStreamChunk
+	if err := json.Unmarshal(chunk, &event); err != nil {
+		return StreamChunk{}, err
+	}
+
+	return StreamChunk{
+		TextDelta: event.OutputText,
+		Done:      event.CompletionReason != "",
+		Usage:     Usage{OutputTokens: event.TotalOutputTextTokenCount},
+	}, nil
+}
+
+func sumTitanOutputTokens(results []TitanResult) int {
+	total := 0
+	for _, r := range results {
+		total += r.TokenCount
+	}
+	return total
+}
+
+// buildPlainTextPrompt はチャット形式をサポートしないモデル向けに、システムプロンプトと
+// 会話履歴をラベル付きのプレーンテキストへ変換する
+func buildPlainTextPrompt(system string, messages []Message, userLabel, assistantLabel string) string {
+	var b strings.Builder
+	if system != "" {
+		b.WriteString(system)
+		b.WriteString("\n\n")
+	}
+	for _, m := range messages {
+		label := userLabel
+		if m.Role == "assistant" {
+			label = assistantLabel
+		}
+		b.WriteString(label)
+		b.WriteString(": ")
+		b.WriteString(m.Text)
+		b.WriteString("\n")
+	}
+	b.WriteString(assistantLabel)
+	b.WriteString(": ")
+	return b.String()
+}
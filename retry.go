@@ -0,0 +1,83 @@
+package main
+
+import (
+	"context"
+	"errors"
+	"math/rand"
+	"time"
+
+	"github.com/aws/aws-sdk-go-v2/service/bedrockruntime/types"
+)
+
+// RetryConfig はBedrock呼び出しのリトライ動作を制御するパラメータ
+type RetryConfig struct {
+	BaseDelay   time.Duration
+	MaxDelay    time.Duration
+	MaxAttempts int
+}
+
+// defaultRetryConfig はRetryConfigのデフォルト値（base 500ms, cap 30s, 最大5回）
+var defaultRetryConfig = RetryConfig{
+	BaseDelay:   500 * time.Millisecond,
+	MaxDelay:    30 * time.Second,
+	MaxAttempts: 5,
+}
+
+// withRetry はfnを実行し、スロットリングなど一時的なエラーの場合のみフルジッター付き指数バックオフで
+// リトライする。ctxがキャンセルされた場合は直ちに処理を打ち切る
+func withRetry(ctx context.Context, cfg RetryConfig, fn func() error) error {
+	var lastErr error
+	for attempt := 0; attempt < cfg.MaxAttempts; attempt++ {
+		if err := ctx.Err(); err != nil {
+			return err
+		}
+
+		err := fn()
+		if err == nil {
+			return nil
+		}
+		lastErr = err
+
+		if !isRetryableError(err) || attempt == cfg.MaxAttempts-1 {
+			return err
+		}
+
+		delay := backoffDelay(cfg, attempt)
+		timer := time.NewTimer(delay)
+		select {
+		case <-ctx.Done():
+			timer.Stop()
+			return ctx.Err()
+		case <-timer.C:
+		}
+	}
+	return lastErr
+}
+
+// backoffDelay は「フルジッター」方式の指数バックオフ待機時間（0〜min(cap, base*2^attempt)の乱数）を計算する
+func backoffDelay(cfg RetryConfig, attempt int) time.Duration {
+	delayCap := cfg.BaseDelay << attempt
+	if delayCap <= 0 || delayCap > cfg.MaxDelay {
+		delayCap = cfg.MaxDelay
+	}
+	return time.Duration(rand.Int63n(int64(delayCap) + 1))
+}
+
+// isRetryableError はスロットリング・タイムアウト・モデル準備中といった一時的なBedrockエラーかどうかを
+// 判定する。ServiceQuotaExceededExceptionはリトライしても解消しないため対象外とする
+func isRetryableError(err error) bool {
+	var throttling *types.ThrottlingException
+	var modelTimeout *types.ModelTimeoutException
+	var modelNotReady *types.ModelNotReadyException
+
+	switch {
+	case errors.As(err, &throttling):
+		return true
+	case errors.As(err, &modelTimeout):
+		return true
+	case errors.As(err, &modelNotReady):
+		return true
+	default:
+		return false
+	}
+}
@@ -0,0 +1,204 @@
+package main
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"os"
+	"time"
+
+	"github.com/aws/aws-sdk-go-v2/aws"
+	"github.com/aws/aws-sdk-go-v2/service/bedrockruntime"
+)
+
+// ToolHandler はClaudeからの tool_use 呼び出しを処理し、tool_result の content として返す値を返す関数
+type ToolHandler func(ctx context.Context, input json.RawMessage) (any, error)
+
+// claudeToolDefinitions はサンプルツール（get_weather, get_time）のJSON Schema定義を返す
+func claudeToolDefinitions() []ClaudeTool {
+	return []ClaudeTool{
+		{
+			Name:        "get_weather",
+			Description: "指定した都市の現在の天気を返す",
+			InputSchema: json.RawMessage(`{
+				"type": "object",
+				"properties": {
+					"city": {"type": "string", "description": "都市名（例: 東京）"}
+				},
+				"required": ["city"]
+			}`),
+		},
+		{
+			Name:        "get_time",
+			Description: "指定したタイムゾーンの現在時刻を返す",
+			InputSchema: json.RawMessage(`{
+				"type": "object",
+				"properties": {
+					"timezone": {"type": "string", "description": "IANAタイムゾーン名（例: Asia/Tokyo）"}
+				},
+				"required": ["timezone"]
+			}`),
+		},
+	}
+}
+
+// defaultToolHandlers はサンプルツール名とハンドラの対応表を返す
+func defaultToolHandlers() map[string]ToolHandler {
+	return map[string]ToolHandler{
+		"get_weather": handleGetWeather,
+		"get_time":    handleGetTime,
+	}
+}
+
+// handleGetWeather はget_weatherツールのハンドラ（デモ用に固定値を返す）
+func handleGetWeather(_ context.Context, input json.RawMessage) (any, error) {
+	var args struct {
+		City string `json:"city"`
+	}
+	if err := json.Unmarshal(input, &args); err != nil {
+		return nil, fmt.Errorf("get_weatherの引数解析に失敗しました: %w", err)
+	}
+
+	return map[string]string{
+		"city":    args.City,
+		"weather": "晴れ",
+		"temp_c":  "27",
+	}, nil
+}
+
+// handleGetTime はget_timeツールのハンドラ
+func handleGetTime(_ context.Context, input json.RawMessage) (any, error) {
+	var args struct {
+		Timezone string `json:"timezone"`
+	}
+	if err := json.Unmarshal(input, &args); err != nil {
+		return nil, fmt.Errorf("get_timeの引数解析に失敗しました: %w", err)
+	}
+
+	loc, err := time.LoadLocation(args.Timezone)
+	if err != nil {
+		return nil, fmt.Errorf("タイムゾーン %q の読み込みに失敗しました: %w", args.Timezone, err)
+	}
+
+	return map[string]string{
+		"timezone": args.Timezone,
+		"time":     time.Now().In(loc).Format(time.RFC3339),
+	}, nil
+}
+
+// runToolLoop はtool_useが止まるまでClaudeの呼び出しとツール実行を繰り返し、最終的な応答テキストを返す
+func runToolLoop(ctx context.Context, client *bedrockruntime.Client, modelID, system, userPrompt string, tools []ClaudeTool, handlers map[string]ToolHandler) (string, error) {
+	request := ClaudeRequest{
+		AnthropicVersion: "bedrock-2023-05-31",
+		MaxTokens:        1024,
+		System:           system,
+		Tools:            tools,
+		Messages: []ClaudeRequestMessage{
+			{
+				Role:    "user",
+				Content: []ClaudeTextContent{{Type: "text", Text: userPrompt}},
+			},
+		},
+	}
+
+	for {
+		body, err := json.Marshal(request)
+		if err != nil {
+			return "", fmt.Errorf("リクエストのJSONエンコードに失敗しました: %w", err)
+		}
+
+		var result *bedrockruntime.InvokeModelOutput
+		err = withRetry(ctx, defaultRetryConfig, func() error {
+			var err error
+			result, err = client.InvokeModel(ctx, &bedrockruntime.InvokeModelInput{
+				ModelId:     aws.String(modelID),
+				ContentType: aws.String("application/json"),
+				Body:        body,
+			})
+			return err
+		})
+		if err != nil {
+			return "", err
+		}
+
+		var response ClaudeResponse
+		if err := json.Unmarshal(result.Body, &response); err != nil {
+			return "", fmt.Errorf("レスポンスの解析に失敗しました: %w", err)
+		}
+
+		request.Messages = append(request.Messages, ClaudeRequestMessage{
+			Role:    "assistant",
+			Content: response.Content,
+		})
+
+		if response.StopReason != "tool_use" {
+			return firstText(response.Content), nil
+		}
+
+		var results []ClaudeTextContent
+		for _, block := range response.Content {
+			if block.Type != "tool_use" {
+				continue
+			}
+
+			handler, ok := handlers[block.Name]
+			if !ok {
+				results = append(results, ClaudeTextContent{
+					Type:      "tool_result",
+					ToolUseID: block.ID,
+					Content:   fmt.Sprintf("エラー: 未登録のツールです: %s", block.Name),
+				})
+				continue
+			}
+
+			output, err := handler(ctx, block.Input)
+			if err != nil {
+				results = append(results, ClaudeTextContent{
+					Type:      "tool_result",
+					ToolUseID: block.ID,
+					Content:   fmt.Sprintf("エラー: %v", err),
+				})
+				continue
+			}
+
+			outputJSON, err := json.Marshal(output)
+			if err != nil {
+				return "", fmt.Errorf("ツール結果のJSONエンコードに失敗しました: %w", err)
+			}
+
+			results = append(results, ClaudeTextContent{
+				Type:      "tool_result",
+				ToolUseID: block.ID,
+				Content:   string(outputJSON),
+			})
+		}
+
+		request.Messages = append(request.Messages, ClaudeRequestMessage{
+			Role:    "user",
+			Content: results,
+		})
+	}
+}
+
+// runToolDemo はget_weather/get_timeツールを使った往復の動作確認用デモを実行する
+func runToolDemo(client *bedrockruntime.Client, modelID string) {
+	const userPrompt = "東京の天気と現在時刻（Asia/Tokyo）を教えてください。"
+
+	text, err := runToolLoop(context.Background(), client, modelID, "", userPrompt, claudeToolDefinitions(), defaultToolHandlers())
+	if err != nil {
+		handleInvokeError(err, modelID)
+		os.Exit(1)
+	}
+
+	fmt.Println(text)
+}
+
+// firstText はコンテンツブロックの中から最初のtextブロックの本文を返す
+func firstText(content []ClaudeTextContent) string {
+	for _, block := range content {
+		if block.Type == "text" {
+			return block.Text
+		}
+	}
+	return ""
+}